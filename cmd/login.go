@@ -3,28 +3,64 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 
+	"github.com/pulumi/pulumi/pkg/backend"
+	_ "github.com/pulumi/pulumi/pkg/backend/blob"
 	"github.com/pulumi/pulumi/pkg/backend/cloud"
+	_ "github.com/pulumi/pulumi/pkg/backend/local"
 	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/workspace"
 )
 
+// currentOrDefaultCloudURL returns the backend URL most recently logged into, if any, so that
+// commands run without an explicit --cloud-url act on the backend the user actually used, rather
+// than always falling back to the Pulumi Cloud.
+func currentOrDefaultCloudURL() (string, error) {
+	current, err := workspace.GetCurrentCloudURL()
+	if err != nil {
+		return "", err
+	}
+	if current != "" {
+		return current, nil
+	}
+	return cloud.DefaultURL(), nil
+}
+
 func newLoginCmd() *cobra.Command {
 	var cloudURL string
+	var authMode string
 	cmd := &cobra.Command{
 		Use:   "login",
 		Short: "Log into the Pulumi Cloud",
-		Long:  "Log into the Pulumi Cloud.  You can script by using PULUMI_ACCESS_TOKEN environment variable.",
-		Args:  cmdutil.NoArgs,
+		Long: "Log into the Pulumi Cloud.  You can script by using the PULUMI_ACCESS_TOKEN environment " +
+			"variable, or log in interactively via a device code or browser flow using --auth-mode.\n" +
+			"\n" +
+			"Passing --cloud-url lets you log into an alternate backend instead, selected by its URL " +
+			"scheme: a self-hosted Pulumi Cloud REST API (https://...), a local filesystem (file://...), " +
+			"or a cloud blob store (s3://..., gs://..., azblob://...).",
+		Args: cmdutil.NoArgs,
 		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
 			if cloudURL == "" {
 				// If no URL was specified, assume it's the default URL.
 				cloudURL = cloud.DefaultURL()
 			}
-			return cloud.Login(cloudURL)
+			b, err := backend.New(cloudURL)
+			if err != nil {
+				return err
+			}
+			if err = b.Login(backend.AuthMode(authMode)); err != nil {
+				return err
+			}
+			// Remember this as the backend later commands should default to.
+			return workspace.SetCurrentCloudURL(cloudURL)
 		}),
 	}
 	cmd.PersistentFlags().StringVarP(&cloudURL, "cloud-url", "c", "", "A cloud URL to log into")
+	cmd.PersistentFlags().StringVar(&authMode, "auth-mode", os.Getenv(backend.AuthModeEnvVar),
+		"The authentication mode to use to log in: token, device, or browser")
 	return cmd
 }
 
@@ -37,10 +73,20 @@ func newLogoutCmd() *cobra.Command {
 		Args:  cmdutil.NoArgs,
 		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
 			if cloudURL == "" {
-				// If no URL was specified, assume it's the default URL.
-				cloudURL = cloud.DefaultURL()
+				// If no URL was specified, default to the backend most recently logged into.
+				var err error
+				if cloudURL, err = currentOrDefaultCloudURL(); err != nil {
+					return err
+				}
+			}
+			b, err := backend.New(cloudURL)
+			if err != nil {
+				return err
+			}
+			if err = b.Logout(); err != nil {
+				return err
 			}
-			return cloud.Logout(cloudURL)
+			return workspace.ClearCurrentCloudURLIfMatches(cloudURL)
 		}),
 	}
 	cmd.PersistentFlags().StringVarP(&cloudURL, "cloud-url", "c", "", "A cloud URL to log out of")
@@ -0,0 +1,43 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+)
+
+func newWhoamiCmd() *cobra.Command {
+	var cloudURL string
+	cmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Display the current logged-in user",
+		Long:  "Display the name of the user currently logged into the selected backend.",
+		Args:  cmdutil.NoArgs,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			if cloudURL == "" {
+				// If no URL was specified, default to the backend most recently logged into.
+				var err error
+				if cloudURL, err = currentOrDefaultCloudURL(); err != nil {
+					return err
+				}
+			}
+			b, err := backend.New(cloudURL)
+			if err != nil {
+				return err
+			}
+			user, err := b.Whoami()
+			if err != nil {
+				return err
+			}
+			fmt.Println(user)
+			return nil
+		}),
+	}
+	cmd.PersistentFlags().StringVarP(&cloudURL, "cloud-url", "c", "", "A cloud URL to check")
+	return cmd
+}
@@ -0,0 +1,79 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+// Package backend defines the interface implemented by every place a stack's state and a user's
+// credentials may live: the hosted Pulumi Cloud, a local filesystem, or a blob store such as S3,
+// GCS, or Azure Blob Storage.
+package backend
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Backend represents the cloud a user is logged into and whose REST API `pulumi` commands such as
+// `login`, `logout`, and `whoami` talk to.  Exactly one Backend is selected per invocation, chosen
+// by the scheme of the target's URL (see New).
+type Backend interface {
+	// Name returns a friendly name identifying this kind of backend, e.g. "cloud" or "local".
+	Name() string
+	// URL returns the URL this backend was constructed with.
+	URL() string
+	// Login authenticates the user against this backend, persisting any credentials obtained.
+	Login(authMode AuthMode) error
+	// Logout removes any credentials persisted for this backend.
+	Logout() error
+	// Whoami returns a friendly description of the currently logged-in user, if any.
+	Whoami() (string, error)
+}
+
+// AuthMode determines how Login obtains credentials, for backends that support more than one way
+// of authenticating a user (tokens pasted by hand, an OAuth2 device code flow, etc).
+type AuthMode string
+
+const (
+	// AuthModeToken reads an access token from the PULUMI_ACCESS_TOKEN environment variable, or
+	// else prompts for one to be pasted in from the backend's console, if it has one.
+	AuthModeToken AuthMode = "token"
+	// AuthModeDevice performs the OAuth2 device authorization grant (RFC 8628), printing a
+	// verification URL and user code for the user to enter in any browser.
+	AuthModeDevice AuthMode = "device"
+	// AuthModeBrowser is like AuthModeDevice, but additionally attempts to open the verification
+	// URL in the user's default browser automatically.
+	AuthModeBrowser AuthMode = "browser"
+
+	// AuthModeEnvVar may be set to choose a default auth mode when --auth-mode is not specified.
+	AuthModeEnvVar = "PULUMI_AUTH_MODE"
+)
+
+// schemeConstructors maps a URL scheme to the function that constructs the Backend responsible
+// for it.  Backend packages register themselves here via RegisterScheme during package init, so
+// that this package need not import every concrete backend.
+var schemeConstructors = make(map[string]func(url string) (Backend, error))
+
+// defaultScheme is used for URLs with no explicit scheme, e.g. the bare "pulumi.com".
+const defaultScheme = "https"
+
+// RegisterScheme registers a constructor function to use for backend URLs with the given scheme,
+// e.g. "file" or "s3". It is expected to be called from the init function of a backend package.
+func RegisterScheme(scheme string, construct func(url string) (Backend, error)) {
+	schemeConstructors[scheme] = construct
+}
+
+// New constructs the Backend responsible for the given URL, selected by its scheme.  URLs with no
+// scheme (or an "https"/"http" scheme) are assumed to refer to a Pulumi Cloud-compatible REST API.
+func New(backendURL string) (Backend, error) {
+	scheme := defaultScheme
+	if u, err := url.Parse(backendURL); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	} else if strings.Contains(backendURL, "://") {
+		return nil, errors.Errorf("malformed backend URL: %s", backendURL)
+	}
+
+	construct, has := schemeConstructors[scheme]
+	if !has {
+		return nil, errors.Errorf("unsupported backend URL scheme %q in %q", scheme, backendURL)
+	}
+	return construct(backendURL)
+}
@@ -0,0 +1,62 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+package backend
+
+import "testing"
+
+type fakeBackend struct{ url string }
+
+func (b *fakeBackend) Name() string                  { return "fake" }
+func (b *fakeBackend) URL() string                   { return b.url }
+func (b *fakeBackend) Login(authMode AuthMode) error { return nil }
+func (b *fakeBackend) Logout() error                 { return nil }
+func (b *fakeBackend) Whoami() (string, error)       { return "", nil }
+
+func TestNewSchemeResolution(t *testing.T) {
+	RegisterScheme("fake-test-scheme", func(url string) (Backend, error) {
+		return &fakeBackend{url: url}, nil
+	})
+
+	cases := []struct {
+		name    string
+		url     string
+		wantErr bool
+		wantURL string
+	}{
+		{"registered scheme", "fake-test-scheme://example.com", false, "fake-test-scheme://example.com"},
+		{"unregistered scheme", "s4://bucket", true, ""},
+		{"malformed URL", "://not-a-url", true, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b, err := New(c.url)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("New(%q) = nil error; expected one", c.url)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New(%q) = %v; expected no error", c.url, err)
+			}
+			if b.URL() != c.wantURL {
+				t.Errorf("New(%q).URL() = %q; expected %q", c.url, b.URL(), c.wantURL)
+			}
+		})
+	}
+}
+
+func TestNewDefaultsBareURLToHTTPSScheme(t *testing.T) {
+	RegisterScheme("https", func(url string) (Backend, error) {
+		return &fakeBackend{url: url}, nil
+	})
+
+	b, err := New("pulumi.com")
+	if err != nil {
+		t.Fatalf("New(\"pulumi.com\") = %v; expected no error", err)
+	}
+	if b.URL() != "pulumi.com" {
+		t.Errorf("New(\"pulumi.com\").URL() = %q; expected %q", b.URL(), "pulumi.com")
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+// Package blob implements the Backend that stores stack state in a cloud blob store, selected by
+// pointing --cloud-url at an "s3://", "gs://", or "azblob://" URL.  Like the local backend, it
+// requires no separate authentication step: access is governed entirely by the ambient cloud
+// provider credentials (e.g. AWS_PROFILE, GOOGLE_APPLICATION_CREDENTIALS, AZURE_STORAGE_ACCOUNT).
+package blob
+
+import (
+	"github.com/pulumi/pulumi/pkg/backend"
+)
+
+func init() {
+	backend.RegisterScheme("s3", New)
+	backend.RegisterScheme("gs", New)
+	backend.RegisterScheme("azblob", New)
+}
+
+// blobBackend is the Backend for an S3, GCS, or Azure Blob Storage bucket URL.  Only Login, Logout,
+// and Whoami are implemented; nothing here reads or writes stack state in the bucket.
+type blobBackend struct {
+	url string
+}
+
+// New returns the Backend that stores state in the bucket named by the given blob storage URL.
+func New(blobURL string) (backend.Backend, error) {
+	return &blobBackend{url: blobURL}, nil
+}
+
+func (b *blobBackend) Name() string { return "blob" }
+func (b *blobBackend) URL() string  { return b.url }
+
+// Login is a no-op for the blob backend: authentication is handled entirely by the ambient cloud
+// provider credentials already configured in the environment.
+func (b *blobBackend) Login(authMode backend.AuthMode) error { return nil }
+
+// Logout is a no-op for the blob backend, since no credentials are ever stored locally.
+func (b *blobBackend) Logout() error { return nil }
+
+// Whoami has no notion of a signed-in user for the blob backend, so it returns the backend URL
+// itself instead.
+func (b *blobBackend) Whoami() (string, error) { return b.url, nil }
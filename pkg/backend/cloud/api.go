@@ -3,16 +3,13 @@
 package cloud
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"net/url"
 	"os"
 	"strings"
 
-	"github.com/golang/glog"
 	"github.com/pkg/errors"
 
 	"github.com/pulumi/pulumi/pkg/backend/cloud/apitype"
@@ -73,44 +70,6 @@ type cloudProjectIdentifier struct {
 	Project    tokens.PackageName
 }
 
-// pulumiAPICall makes an HTTP request to the Pulumi API.
-func pulumiAPICall(cloudAPI, method, path string, body []byte, accessToken string) (string, *http.Response, error) {
-	apiEndpoint, err := getCloudAPI(cloudAPI)
-	if err != nil {
-		return "", nil, fmt.Errorf("getting Pulumi API endpoint: %v", err)
-	}
-
-	// Normalize URL components
-	apiEndpoint = strings.TrimSuffix(apiEndpoint, "/")
-	path = strings.TrimPrefix(path, "/")
-
-	url := fmt.Sprintf("%s/api/%s", apiEndpoint, path)
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
-	if err != nil {
-		return "", nil, fmt.Errorf("creating new HTTP request: %v", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	// Apply credentials if provided.
-	if accessToken != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("token %s", accessToken))
-	}
-
-	glog.V(7).Infof("Making Pulumi API call: %s", url)
-	if glog.V(9) {
-		glog.V(9).Infof("Pulumi API call details (%s): headers=%v; body=%v", url, req.Header, string(body))
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", nil, fmt.Errorf("performing HTTP request: %v", err)
-	}
-	glog.V(7).Infof("Pulumi API call response code (%s): %v", url, resp.Status)
-
-	return url, resp, nil
-}
-
 // pulumiRESTCall calls the pulumi REST API marshalling reqObj to JSON and using that as
 // the request body (use nil for GETs), and if successful, marshalling the responseObj
 // as JSON and storing it in respObj (use nil for NoContent). The error return type might
@@ -126,6 +85,13 @@ func pulumiRESTCall(cloudAPI, method, path string, reqObj interface{}, respObj i
 // pulumiRESTCallWithAccessToken requires you pass in the auth token rather than reading it from the machine's config.
 func pulumiRESTCallWithAccessToken(cloudAPI, method, path string,
 	reqObj interface{}, respObj interface{}, token string) error {
+	return pulumiRESTCallWithAccessTokenAndRefresh(cloudAPI, method, path, reqObj, respObj, token, true /*allowRefresh*/)
+}
+
+// pulumiRESTCallWithAccessTokenAndRefresh is like pulumiRESTCallWithAccessToken, but lets callers
+// (namely the refresh logic itself) opt out of attempting a 401 refresh, to avoid looping forever.
+func pulumiRESTCallWithAccessTokenAndRefresh(cloudAPI, method, path string,
+	reqObj interface{}, respObj interface{}, token string, allowRefresh bool) error {
 	var reqBody []byte
 	var err error
 	if reqObj != nil {
@@ -135,7 +101,7 @@ func pulumiRESTCallWithAccessToken(cloudAPI, method, path string,
 		}
 	}
 
-	url, resp, err := pulumiAPICall(cloudAPI, method, path, reqBody, token)
+	_, resp, err := pulumiAPICall(cloudAPI, method, path, reqBody, token)
 	if err != nil {
 		return fmt.Errorf("calling API: %v", err)
 	}
@@ -145,15 +111,20 @@ func pulumiRESTCallWithAccessToken(cloudAPI, method, path string,
 	if err != nil {
 		return fmt.Errorf("reading response from API: %v", err)
 	}
-	if glog.V(9) {
-		glog.V(7).Infof("Pulumi API call response body (%s): %v", url, string(respBody))
-	}
+	logAPIResponseBody(method, path, resp.Header.Get(requestIDHeader), respBody)
 
 	// 4xx and 5xx responses should be of type ErrorResponse. See if we can unmarshal as that
 	// type, and if not just return the raw response text.
 	if resp.StatusCode >= 400 && resp.StatusCode <= 599 {
 		if resp.StatusCode == 401 {
-			// Special case "unauthorized", and direct the developer to login.
+			// If we have a refresh token for this cloud, try exchanging it for a new access
+			// token and retrying the call once before giving up and asking the user to log in.
+			if allowRefresh {
+				if newToken, rerr := refreshAccessToken(cloudAPI); rerr == nil {
+					return pulumiRESTCallWithAccessTokenAndRefresh(
+						cloudAPI, method, path, reqObj, respObj, newToken, false /*allowRefresh*/)
+				}
+			}
 			return errors.New("this command requires logging in; try running 'pulumi login' first")
 		}
 
@@ -173,3 +144,72 @@ func pulumiRESTCallWithAccessToken(cloudAPI, method, path string,
 
 	return nil
 }
+
+// pulumiRESTCallForDeviceToken is like pulumiRESTCallWithAccessToken, but for the OAuth2 device
+// authorization poll endpoint (RFC 8628 §3.4), whose "please wait" and error responses come back
+// as HTTP 400 with a JSON body carrying an RFC 6749 §5.2 "error" field (see deviceTokenResponse),
+// not as an apitype.ErrorResponse.  Unlike the generic REST path, a 400 here is unmarshalled into
+// respObj so the caller can inspect its Error field, rather than being treated as a terminal
+// failure; only a genuinely unexpected 5xx is surfaced as a call error.
+func pulumiRESTCallForDeviceToken(cloudAPI, path string, reqObj interface{}, respObj interface{}) error {
+	reqBody, err := json.Marshal(reqObj)
+	if err != nil {
+		return fmt.Errorf("marshalling request object as JSON: %v", err)
+	}
+
+	_, resp, err := pulumiAPICall(cloudAPI, "POST", path, reqBody, "")
+	if err != nil {
+		return fmt.Errorf("calling API: %v", err)
+	}
+	defer contract.IgnoreClose(resp.Body)
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response from API: %v", err)
+	}
+	logAPIResponseBody("POST", path, resp.Header.Get(requestIDHeader), respBody)
+
+	if resp.StatusCode >= 500 {
+		var errResp apitype.ErrorResponse
+		if err = json.Unmarshal(respBody, &errResp); err != nil {
+			errResp.Code = resp.StatusCode
+			errResp.Message = strings.TrimSpace(string(respBody))
+		}
+		return &errResp
+	}
+
+	if err = json.Unmarshal(respBody, respObj); err != nil {
+		return fmt.Errorf("unmarshalling response object: %v", err)
+	}
+	return nil
+}
+
+// refreshAccessToken exchanges the refresh token stored for cloudAPI, if any, for a new access
+// token, persisting it (along with any rotated refresh token) before returning it.
+func refreshAccessToken(cloudAPI string) (string, error) {
+	refreshToken, err := workspace.GetRefreshToken(cloudAPI)
+	if err != nil {
+		return "", err
+	} else if refreshToken == "" {
+		return "", errors.New("no refresh token available")
+	}
+
+	var resp deviceTokenResponse
+	req := map[string]string{"grant_type": "refresh_token", "refresh_token": refreshToken}
+	if err = pulumiRESTCallWithAccessTokenAndRefresh(
+		cloudAPI, "POST", "cli/oauth/token", req, &resp, "", false /*allowRefresh*/); err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", errors.New("refresh response did not include an access token")
+	}
+
+	newRefreshToken := refreshToken
+	if resp.RefreshToken != "" {
+		newRefreshToken = resp.RefreshToken
+	}
+	if err = workspace.StoreAccessToken(cloudAPI, resp.AccessToken, newRefreshToken); err != nil {
+		return "", err
+	}
+	return resp.AccessToken, nil
+}
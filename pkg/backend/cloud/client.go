@@ -0,0 +1,194 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+package cloud
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+const (
+	// requestIDHeader is the header the Pulumi Cloud echoes back on every response, identifying
+	// the request for correlation with server-side logs.
+	requestIDHeader = "X-Pulumi-Request-Id"
+
+	// defaultMaxRetries is the number of times a retryable request is retried before giving up.
+	defaultMaxRetries = 5
+	// baseRetryDelay is the initial delay used for exponential backoff between retries.
+	baseRetryDelay = 500 * time.Millisecond
+	// maxRetryDelay caps how long any single backoff (including a server-provided Retry-After) may be.
+	maxRetryDelay = 30 * time.Second
+)
+
+// httpClient is shared across all API calls, rather than allocating a new client (and its
+// underlying connection pool) per call.
+var httpClient = &http.Client{Timeout: 60 * time.Second}
+
+// apiCallOptions controls how pulumiAPICall retries a request.  The zero value is not usable;
+// callers should start from defaultAPICallOptions, which is what every production call site does.
+// Tests may override client/now/sleep/random to make retry behavior deterministic.
+type apiCallOptions struct {
+	client     *http.Client
+	maxRetries int
+	now        func() time.Time
+	sleep      func(time.Duration)
+	random     func() float64
+}
+
+// defaultAPICallOptions returns the retry configuration used by all real Pulumi API calls.
+func defaultAPICallOptions() apiCallOptions {
+	return apiCallOptions{
+		client:     httpClient,
+		maxRetries: defaultMaxRetries,
+		now:        time.Now,
+		sleep:      time.Sleep,
+		random:     rand.Float64,
+	}
+}
+
+// apiRequestRecord is a structured log line describing a single attempt at an API call, replacing
+// the ad-hoc glog.V(7)/glog.V(9) calls that used to be scattered through this file.  RequestBody is
+// only logged at V(9); request headers (which may carry the access token) are deliberately never
+// logged at any verbosity.
+type apiRequestRecord struct {
+	Method      string
+	Path        string
+	Status      int
+	Duration    time.Duration
+	RequestID   string
+	Attempt     int
+	RequestBody string
+}
+
+func (r apiRequestRecord) log() {
+	glog.V(7).Infof("pulumi api call: method=%s path=%s status=%d duration=%s request-id=%s attempt=%d",
+		r.Method, r.Path, r.Status, r.Duration, r.RequestID, r.Attempt)
+	if glog.V(9) {
+		glog.V(9).Infof("pulumi api call detail: method=%s path=%s request-id=%s request-body=%s",
+			r.Method, r.Path, r.RequestID, r.RequestBody)
+	}
+}
+
+// logAPIResponseBody logs the body of a completed API call's response, once the caller has read
+// it, in the same structured style as apiRequestRecord rather than as an ad-hoc string dump.
+func logAPIResponseBody(method, path, requestID string, body []byte) {
+	if glog.V(9) {
+		glog.V(9).Infof("pulumi api call detail: method=%s path=%s request-id=%s response-body=%s",
+			method, path, requestID, string(body))
+	}
+}
+
+// pulumiAPICall makes an HTTP request to the Pulumi API, retrying transient failures (network
+// errors, 5xx responses, and 429s) with exponential backoff and jitter, honoring a Retry-After
+// the server sends back with a 429 or 503.
+func pulumiAPICall(cloudAPI, method, path string, body []byte, accessToken string) (string, *http.Response, error) {
+	return pulumiAPICallWithOptions(cloudAPI, method, path, body, accessToken, defaultAPICallOptions())
+}
+
+func pulumiAPICallWithOptions(cloudAPI, method, path string, body []byte, accessToken string,
+	opts apiCallOptions) (string, *http.Response, error) {
+	apiEndpoint, err := getCloudAPI(cloudAPI)
+	if err != nil {
+		return "", nil, fmt.Errorf("getting Pulumi API endpoint: %v", err)
+	}
+
+	// Normalize URL components
+	apiEndpoint = strings.TrimSuffix(apiEndpoint, "/")
+	path = strings.TrimPrefix(path, "/")
+	url := fmt.Sprintf("%s/api/%s", apiEndpoint, path)
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, url, bytes.NewReader(body))
+		if err != nil {
+			return "", nil, fmt.Errorf("creating new HTTP request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if accessToken != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("token %s", accessToken))
+		}
+
+		start := opts.now()
+		resp, err := opts.client.Do(req)
+		duration := opts.now().Sub(start)
+
+		if err != nil {
+			if attempt >= opts.maxRetries {
+				return "", nil, fmt.Errorf("performing HTTP request: %v", err)
+			}
+			opts.sleep(backoffDelay(attempt, opts.random))
+			continue
+		}
+
+		apiRequestRecord{
+			Method: method, Path: path, Status: resp.StatusCode, Duration: duration,
+			RequestID: resp.Header.Get(requestIDHeader), Attempt: attempt, RequestBody: string(body),
+		}.log()
+
+		if isRetryableStatus(resp.StatusCode) && attempt < opts.maxRetries {
+			delay := retryDelay(resp, attempt, opts)
+			contract.IgnoreClose(resp.Body)
+			opts.sleep(delay)
+			continue
+		}
+
+		return url, resp, nil
+	}
+}
+
+// isRetryableStatus returns true for status codes worth retrying: 429 (rate limited), 503
+// (service unavailable), and any other 5xx (transient server error).
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || (status >= 500 && status <= 599)
+}
+
+// retryDelay determines how long to wait before the next attempt, honoring a Retry-After header
+// on 429/503 responses if the server sent one, and otherwise falling back to exponential backoff.
+func retryDelay(resp *http.Response, attempt int, opts apiCallOptions) time.Duration {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), opts.now()); ok {
+			return capDelay(d)
+		}
+	}
+	return backoffDelay(attempt, opts.random)
+}
+
+// parseRetryAfter parses a Retry-After header value, which per RFC 7231 may be either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		if d := when.Sub(now); d > 0 {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// backoffDelay computes an exponentially increasing delay with jitter for the given retry attempt
+// (0-indexed), capped at maxRetryDelay.
+func backoffDelay(attempt int, random func() float64) time.Duration {
+	delay := baseRetryDelay * time.Duration(1<<uint(attempt))
+	jitter := time.Duration(random() * float64(delay))
+	return capDelay(delay + jitter)
+}
+
+func capDelay(d time.Duration) time.Duration {
+	if d > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return d
+}
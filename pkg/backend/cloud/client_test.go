@@ -0,0 +1,195 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+package cloud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// roundTripFunc adapts a function to the http.RoundTripper interface, so tests can redirect
+// outgoing requests to an httptest.Server without needing getCloudAPI to know about it.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// testClient returns an *http.Client that sends every request to ts, regardless of the host and
+// scheme getCloudAPI rewrote the request URL to.
+func testClient(ts *httptest.Server) *http.Client {
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		panic(err)
+	}
+	return &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			req.URL.Scheme = tsURL.Scheme
+			req.URL.Host = tsURL.Host
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	noJitter := func() float64 { return 0 }
+
+	cases := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{0, baseRetryDelay},
+		{1, 2 * baseRetryDelay},
+		{2, 4 * baseRetryDelay},
+	}
+	for _, c := range cases {
+		if got := backoffDelay(c.attempt, noJitter); got != c.expected {
+			t.Errorf("backoffDelay(%d, noJitter) = %s; expected %s", c.attempt, got, c.expected)
+		}
+	}
+
+	// A large attempt count must be capped at maxRetryDelay rather than overflowing or growing
+	// without bound.
+	if got := backoffDelay(20, noJitter); got != maxRetryDelay {
+		t.Errorf("backoffDelay(20, noJitter) = %s; expected cap of %s", got, maxRetryDelay)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		header   string
+		expected time.Duration
+		ok       bool
+	}{
+		{"empty", "", 0, false},
+		{"delta-seconds", "120", 120 * time.Second, true},
+		{"http-date-future", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{"http-date-past", now.Add(-90 * time.Second).Format(http.TimeFormat), 0, false},
+		{"garbage", "not-a-valid-value", 0, false},
+	}
+	for _, c := range cases {
+		d, ok := parseRetryAfter(c.header, now)
+		if ok != c.ok {
+			t.Errorf("%s: parseRetryAfter ok = %v; expected %v", c.name, ok, c.ok)
+			continue
+		}
+		if ok && d != c.expected {
+			t.Errorf("%s: parseRetryAfter = %s; expected %s", c.name, d, c.expected)
+		}
+	}
+}
+
+// fakeClock lets a test control time.Now and time.Sleep, recording every sleep so assertions can
+// be made about the backoff schedule actually used.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+func testOptions(client *http.Client, clock *fakeClock) apiCallOptions {
+	return apiCallOptions{
+		client:     client,
+		maxRetries: defaultMaxRetries,
+		now:        clock.Now,
+		sleep:      clock.Sleep,
+		random:     func() float64 { return 0 },
+	}
+}
+
+func TestPulumiAPICallWithOptionsRetriesTransientErrors(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	_, resp, err := pulumiAPICallWithOptions("http://example.test", "GET", "stacks", nil, "",
+		testOptions(testClient(ts), clock))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts (2 failures then a success); got %d", attempts)
+	}
+	if len(clock.sleeps) != 2 {
+		t.Fatalf("expected 2 retries to have slept; got %d", len(clock.sleeps))
+	}
+	if clock.sleeps[0] != baseRetryDelay || clock.sleeps[1] != 2*baseRetryDelay {
+		t.Errorf("unexpected backoff schedule: %v", clock.sleeps)
+	}
+}
+
+func TestPulumiAPICallWithOptionsHonorsRetryAfter(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 2 {
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	_, resp, err := pulumiAPICallWithOptions("http://example.test", "GET", "stacks", nil, "",
+		testOptions(testClient(ts), clock))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if len(clock.sleeps) != 1 || clock.sleeps[0] != 5*time.Second {
+		t.Errorf("expected a single 5s sleep honoring Retry-After; got %v", clock.sleeps)
+	}
+}
+
+func TestPulumiAPICallWithOptionsGivesUpAfterMaxRetries(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	opts := testOptions(testClient(ts), clock)
+	opts.maxRetries = 2
+
+	_, resp, err := pulumiAPICallWithOptions("http://example.test", "GET", "stacks", nil, "", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the final 503 to be returned once retries are exhausted; got %d", resp.StatusCode)
+	}
+	if attempts != opts.maxRetries+1 {
+		t.Errorf("expected %d attempts (initial plus %d retries); got %d", opts.maxRetries+1, opts.maxRetries, attempts)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+package cloud
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+func init() {
+	backend.RegisterScheme("https", New)
+	backend.RegisterScheme("http", New)
+}
+
+// cloudBackend is the Backend implementation that talks to the Pulumi Cloud (or a Cloud-compatible
+// REST API) at a given URL.
+type cloudBackend struct {
+	url string
+}
+
+// New returns the Backend that talks to the Pulumi Cloud (or Cloud-compatible) REST API at cloudURL.
+func New(cloudURL string) (backend.Backend, error) {
+	return &cloudBackend{url: cloudURL}, nil
+}
+
+func (b *cloudBackend) Name() string { return "cloud" }
+func (b *cloudBackend) URL() string  { return b.url }
+
+func (b *cloudBackend) Login(authMode backend.AuthMode) error { return Login(b.url, authMode) }
+func (b *cloudBackend) Logout() error                         { return Logout(b.url) }
+
+func (b *cloudBackend) Whoami() (string, error) { return CurrentUser(b.url) }
+
+// whoamiResponse is returned by the GET /api/user endpoint.
+type whoamiResponse struct {
+	GithubLogin string `json:"githubLogin"`
+}
+
+// CurrentUser returns the name of the user currently logged into the cloud at cloudURL, querying
+// the GET /api/user endpoint and caching the result locally for future reference.
+func CurrentUser(cloudURL string) (string, error) {
+	var resp whoamiResponse
+	if err := pulumiRESTCall(cloudURL, "GET", "user", nil, &resp); err != nil {
+		return "", err
+	}
+
+	if account, err := workspace.GetAccount(cloudURL); err == nil {
+		account.Username = resp.GithubLogin
+		account.LastValidatedAt = time.Now()
+		_ = workspace.StoreAccount(cloudURL, account)
+	}
+
+	return resp.GithubLogin, nil
+}
+
+// deviceCodeResponse is returned by the cloud's device authorization endpoint.
+type deviceCodeResponse struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// deviceTokenResponse is returned by the cloud's device token endpoint.  Per RFC 8628, an
+// in-progress poll comes back as an error of "authorization_pending" or "slow_down" rather than
+// an HTTP failure, so this is populated on both success and "not yet" responses.
+type deviceTokenResponse struct {
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Login logs into the cloud at cloudURL, storing the resulting credentials for use by subsequent
+// commands.  If PULUMI_ACCESS_TOKEN is set, it is used directly; otherwise, the user is
+// authenticated interactively using authMode (falling back to PULUMI_AUTH_MODE, then AuthModeToken).
+func Login(cloudURL string, authMode backend.AuthMode) error {
+	if accessToken := os.Getenv(AccessTokenEnvVar); accessToken != "" {
+		return workspace.StoreAccessToken(cloudURL, accessToken, "")
+	}
+
+	if authMode == "" {
+		if envMode := os.Getenv(backend.AuthModeEnvVar); envMode != "" {
+			authMode = backend.AuthMode(envMode)
+		} else {
+			authMode = backend.AuthModeToken
+		}
+	}
+
+	switch authMode {
+	case backend.AuthModeToken:
+		return tokenLogin(cloudURL)
+	case backend.AuthModeDevice:
+		return deviceLogin(cloudURL, false /*openInBrowser*/)
+	case backend.AuthModeBrowser:
+		return deviceLogin(cloudURL, true /*openInBrowser*/)
+	default:
+		return errors.Errorf("unknown --auth-mode %q; expected one of token, device, browser", authMode)
+	}
+}
+
+// Logout logs out of the cloud at cloudURL, deleting any credentials stored for it.
+func Logout(cloudURL string) error {
+	return workspace.DeleteAccessToken(cloudURL)
+}
+
+// tokenLogin prompts the user to paste an access token generated from the Pulumi Cloud console,
+// falling back to the device authorization flow if the user just presses enter.
+func tokenLogin(cloudURL string) error {
+	fmt.Printf("Manage your Pulumi stacks by logging in.\n")
+	fmt.Printf("Enter your access token, or press enter to log in using your browser instead:\n")
+	fmt.Print("Access token: ")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return errors.Wrap(err, "reading access token")
+	}
+
+	if accessToken := strings.TrimSpace(line); accessToken != "" {
+		return workspace.StoreAccessToken(cloudURL, accessToken, "")
+	}
+	return deviceLogin(cloudURL, true /*openInBrowser*/)
+}
+
+// deviceLogin performs the OAuth2 device authorization grant (RFC 8628) against cloudURL.
+func deviceLogin(cloudURL string, openInBrowser bool) error {
+	device, err := requestDeviceCode(cloudURL)
+	if err != nil {
+		return errors.Wrap(err, "requesting device code")
+	}
+
+	fmt.Printf("To authenticate, visit %s and enter the code: %s\n", device.VerificationURI, device.UserCode)
+	if openInBrowser {
+		if err = openBrowser(device.VerificationURI); err != nil {
+			glog.V(7).Infof("could not open browser automatically: %v", err)
+		}
+	}
+
+	accessToken, refreshToken, err := pollForDeviceToken(cloudURL, device)
+	if err != nil {
+		return err
+	}
+	return workspace.StoreAccessToken(cloudURL, accessToken, refreshToken)
+}
+
+// requestDeviceCode requests a device and user code from the cloud's device authorization endpoint.
+func requestDeviceCode(cloudURL string) (*deviceCodeResponse, error) {
+	var resp deviceCodeResponse
+	if err := pulumiRESTCallWithAccessToken(cloudURL, "POST", "cli/oauth/device", nil, &resp, ""); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// pollForDeviceToken polls the cloud's device token endpoint until the user completes
+// authorization in a browser, per the RFC 8628 device authorization grant.
+func pollForDeviceToken(cloudURL string, device *deviceCodeResponse) (string, string, error) {
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		var resp deviceTokenResponse
+		req := map[string]string{"device_code": device.DeviceCode}
+		if err := pulumiRESTCallForDeviceToken(cloudURL, "cli/oauth/device/token", req, &resp); err != nil {
+			return "", "", err
+		}
+
+		switch resp.Error {
+		case "":
+			return resp.AccessToken, resp.RefreshToken, nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+		case "expired_token":
+			return "", "", errors.New("the device code expired before authorization completed; please try again")
+		case "access_denied":
+			return "", "", errors.New("authorization was denied")
+		default:
+			return "", "", errors.Errorf("unexpected error polling for device token: %s", resp.Error)
+		}
+	}
+
+	return "", "", errors.New("timed out waiting for the device to be authorized")
+}
+
+// openBrowser attempts to open url in the user's default browser, on a best-effort basis.
+func openBrowser(url string) error {
+	var cmd string
+	var args []string
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = "open"
+	case "windows":
+		cmd, args = "rundll32", []string{"url.dll,FileProtocolHandler"}
+	default:
+		cmd = "xdg-open"
+	}
+
+	args = append(args, url)
+	return exec.Command(cmd, args...).Start()
+}
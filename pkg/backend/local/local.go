@@ -0,0 +1,61 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+// Package local implements the Backend that stores stack state on the local filesystem, selected
+// by pointing --cloud-url at a "file://" URL, e.g. "file://~/.pulumi-state".
+package local
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+)
+
+func init() {
+	backend.RegisterScheme("file", New)
+}
+
+// localBackend is the Backend implementation that stores stack state under a directory on the
+// local filesystem rather than talking to a remote REST API.  It requires no authentication: Login
+// only ensures the directory exists, Logout does nothing, and Whoami reports the local machine's
+// current user.  Only Login, Logout, and Whoami are implemented; nothing here reads or writes
+// stack state.
+type localBackend struct {
+	url string
+	dir string
+}
+
+// New returns the Backend that stores state under the directory named by the "file://" URL.
+func New(fileURL string) (backend.Backend, error) {
+	dir := strings.TrimPrefix(fileURL, "file://")
+	if dir == "" {
+		return nil, errors.New("file:// backend URL must name a directory, e.g. file://~/.pulumi-state")
+	}
+
+	dir, err := expandHome(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "expanding local state directory")
+	}
+	return &localBackend{url: fileURL, dir: dir}, nil
+}
+
+func (b *localBackend) Name() string { return "local" }
+func (b *localBackend) URL() string  { return b.url }
+
+// Login for the local backend authenticates nothing: there's no remote service to authenticate
+// against, so it simply confirms the backing directory exists (creating it if this is the first use).
+func (b *localBackend) Login(authMode backend.AuthMode) error {
+	return errors.Wrap(ensureDir(b.dir), "initializing local state directory")
+}
+
+// Logout for the local backend is a no-op, since no credentials are ever stored.
+func (b *localBackend) Logout() error {
+	return nil
+}
+
+// Whoami returns the local machine's current user, since the local backend has no notion of
+// multi-user accounts.
+func (b *localBackend) Whoami() (string, error) {
+	return currentOSUser()
+}
@@ -0,0 +1,41 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+package local
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ensureDir creates dir, and any missing parents, if it does not already exist.
+func ensureDir(dir string) error {
+	return os.MkdirAll(dir, 0700)
+}
+
+// currentOSUser returns the username of whoever is running the CLI.
+func currentOSUser() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", errors.Wrap(err, "getting current user")
+	}
+	return u.Username, nil
+}
+
+// expandHome expands a leading "~" in dir into the current user's home directory, so that a
+// "file://~/.pulumi-state" URL resolves to the right place rather than a literal "~" directory
+// relative to the process's current working directory.
+func expandHome(dir string) (string, error) {
+	if dir != "~" && !strings.HasPrefix(dir, "~/") {
+		return dir, nil
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return "", errors.Wrap(err, "getting current user")
+	}
+	return filepath.Join(u.HomeDir, strings.TrimPrefix(dir, "~")), nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+package workspace
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// credentialsFile is the name of the file, relative to the Pulumi home directory, where access
+// tokens are stored on the local machine.
+const credentialsFile = "credentials.json"
+
+// Account holds the state required to authenticate against, and identify the caller to, a single
+// backend.  A machine may be logged into more than one backend (Pulumi Cloud, a self-hosted
+// instance, etc) at once, so Accounts are keyed by the backend's URL; see credentials below.
+type Account struct {
+	AccessToken     string    `json:"accessToken,omitempty"`
+	RefreshToken    string    `json:"refreshToken,omitempty"`
+	Username        string    `json:"username,omitempty"`
+	LastValidatedAt time.Time `json:"lastValidatedAt,omitempty"`
+}
+
+// credentials is the on-disk format of the credentials file.  Accounts are keyed by the backend
+// URL they authenticate against.  Current records the URL of the backend most recently logged
+// into, so that commands run without an explicit --cloud-url default to it rather than always
+// falling back to the Pulumi Cloud.
+type credentials struct {
+	Current  string              `json:"current,omitempty"`
+	Accounts map[string]Account `json:"accounts,omitempty"`
+}
+
+// userHomeDir returns the current user's home directory.  It is a variable, rather than a direct
+// call to user.Current, so that tests can point getCredsFilePath at a temporary directory.
+var userHomeDir = func() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", errors.Wrap(err, "getting current user")
+	}
+	return u.HomeDir, nil
+}
+
+// getCredsFilePath returns the path to the file where Pulumi stores credentials on the local machine.
+func getCredsFilePath() (string, error) {
+	home, err := userHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".pulumi", credentialsFile), nil
+}
+
+// getCredentials reads the credentials file, returning a zero value if it does not yet exist.
+func getCredentials() (credentials, error) {
+	path, err := getCredsFilePath()
+	if err != nil {
+		return credentials{}, err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return credentials{}, nil
+		}
+		return credentials{}, errors.Wrap(err, "reading credentials file")
+	}
+
+	var creds credentials
+	if err = json.Unmarshal(b, &creds); err != nil {
+		return credentials{}, errors.Wrap(err, "unmarshalling credentials file")
+	}
+	return creds, nil
+}
+
+// storeCredentials overwrites the credentials file with the given contents.
+func storeCredentials(creds credentials) error {
+	path, err := getCredsFilePath()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "creating credentials directory")
+	}
+
+	b, err := json.MarshalIndent(creds, "", "    ")
+	if err != nil {
+		return errors.Wrap(err, "marshalling credentials file")
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// GetCurrentCloudURL returns the URL of the backend most recently recorded by StoreAccessToken or
+// SetCurrentCloudURL, or "" if none has been recorded yet (or the user has since logged out of the
+// backend that was current, even if still logged into others).
+func GetCurrentCloudURL() (string, error) {
+	creds, err := getCredentials()
+	if err != nil {
+		return "", err
+	}
+	return creds.Current, nil
+}
+
+// SetCurrentCloudURL records cloudURL as the backend that subsequent commands should default to
+// when run without an explicit --cloud-url.
+func SetCurrentCloudURL(cloudURL string) error {
+	creds, err := getCredentials()
+	if err != nil {
+		return err
+	}
+	creds.Current = cloudURL
+	return storeCredentials(creds)
+}
+
+// ClearCurrentCloudURLIfMatches unsets the current backend if it is cloudURL, so that a backend
+// which has just been logged out of is no longer the default for commands run without
+// --cloud-url.  It is a no-op if some other backend is current.
+func ClearCurrentCloudURLIfMatches(cloudURL string) error {
+	creds, err := getCredentials()
+	if err != nil {
+		return err
+	}
+	if creds.Current != cloudURL {
+		return nil
+	}
+	creds.Current = ""
+	return storeCredentials(creds)
+}
+
+// GetAccount returns the stored account for the given backend URL, if one exists.
+func GetAccount(cloudURL string) (Account, error) {
+	creds, err := getCredentials()
+	if err != nil {
+		return Account{}, err
+	}
+	return creds.Accounts[cloudURL], nil
+}
+
+// GetAccessToken returns the access token for the given backend URL, if one is stored locally.
+func GetAccessToken(cloudURL string) (string, error) {
+	account, err := GetAccount(cloudURL)
+	if err != nil {
+		return "", err
+	}
+	return account.AccessToken, nil
+}
+
+// GetRefreshToken returns the refresh token for the given backend URL, if one is stored locally.
+func GetRefreshToken(cloudURL string) (string, error) {
+	account, err := GetAccount(cloudURL)
+	if err != nil {
+		return "", err
+	}
+	return account.RefreshToken, nil
+}
+
+// StoreAccount saves the given account, so that it may be used to authenticate future requests to
+// the backend at cloudURL, and replaces the previous account stored for that URL, if any.
+func StoreAccount(cloudURL string, account Account) error {
+	creds, err := getCredentials()
+	if err != nil {
+		return err
+	}
+
+	if creds.Accounts == nil {
+		creds.Accounts = make(map[string]Account)
+	}
+	creds.Accounts[cloudURL] = account
+
+	return storeCredentials(creds)
+}
+
+// StoreAccessToken saves the given access token, and the given refresh token if non-empty, for the
+// backend at cloudURL, preserving any other account details (such as username) already stored, and
+// marks cloudURL as the current backend.
+func StoreAccessToken(cloudURL, accessToken, refreshToken string) error {
+	account, err := GetAccount(cloudURL)
+	if err != nil {
+		return err
+	}
+
+	account.AccessToken = accessToken
+	if refreshToken != "" {
+		account.RefreshToken = refreshToken
+	}
+
+	if err = StoreAccount(cloudURL, account); err != nil {
+		return err
+	}
+	return SetCurrentCloudURL(cloudURL)
+}
+
+// DeleteAccessToken removes the stored account for the given backend URL, if any.  If cloudURL was
+// the current backend, it is no longer the default for commands run without --cloud-url.
+func DeleteAccessToken(cloudURL string) error {
+	creds, err := getCredentials()
+	if err != nil {
+		return err
+	}
+	delete(creds.Accounts, cloudURL)
+	if creds.Current == cloudURL {
+		creds.Current = ""
+	}
+	return storeCredentials(creds)
+}
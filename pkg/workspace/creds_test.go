@@ -0,0 +1,134 @@
+// Copyright 2016-2017, Pulumi Corporation.  All rights reserved.
+
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// withTempHome points userHomeDir at a fresh temporary directory for the duration of a test, so
+// credential storage tests never touch the real machine's ~/.pulumi.  Callers must defer the
+// returned func to restore the real userHomeDir and clean up the temporary directory.
+func withTempHome(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "pulumi-creds-test")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+
+	prevHomeDir := userHomeDir
+	userHomeDir = func() (string, error) { return dir, nil }
+
+	return func() {
+		userHomeDir = prevHomeDir
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStoreAccessTokenSetsCurrent(t *testing.T) {
+	defer withTempHome(t)()
+
+	const cloudURL = "https://api.pulumi.com"
+	if err := StoreAccessToken(cloudURL, "access-token", "refresh-token"); err != nil {
+		t.Fatalf("StoreAccessToken: %v", err)
+	}
+
+	current, err := GetCurrentCloudURL()
+	if err != nil {
+		t.Fatalf("GetCurrentCloudURL: %v", err)
+	}
+	if current != cloudURL {
+		t.Errorf("GetCurrentCloudURL() = %q; expected %q", current, cloudURL)
+	}
+
+	account, err := GetAccount(cloudURL)
+	if err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	if account.AccessToken != "access-token" || account.RefreshToken != "refresh-token" {
+		t.Errorf("GetAccount(%q) = %+v; expected tokens to be stored", cloudURL, account)
+	}
+}
+
+func TestStoreAccessTokenSwitchesCurrentBackend(t *testing.T) {
+	defer withTempHome(t)()
+
+	const first = "https://api.pulumi.com"
+	const second = "file://~/.pulumi-state"
+
+	if err := StoreAccessToken(first, "token-1", ""); err != nil {
+		t.Fatalf("StoreAccessToken(%q): %v", first, err)
+	}
+	if err := StoreAccessToken(second, "token-2", ""); err != nil {
+		t.Fatalf("StoreAccessToken(%q): %v", second, err)
+	}
+
+	current, err := GetCurrentCloudURL()
+	if err != nil {
+		t.Fatalf("GetCurrentCloudURL: %v", err)
+	}
+	if current != second {
+		t.Errorf("GetCurrentCloudURL() = %q; expected the most recently logged-into backend %q", current, second)
+	}
+
+	// The first account should still be there; logging into a second backend must not evict it.
+	token, err := GetAccessToken(first)
+	if err != nil {
+		t.Fatalf("GetAccessToken(%q): %v", first, err)
+	}
+	if token != "token-1" {
+		t.Errorf("GetAccessToken(%q) = %q; expected %q", first, token, "token-1")
+	}
+}
+
+func TestDeleteAccessTokenClearsCurrentOnlyIfItMatches(t *testing.T) {
+	defer withTempHome(t)()
+
+	const first = "https://api.pulumi.com"
+	const second = "https://self-hosted.example.com"
+
+	if err := StoreAccessToken(first, "token-1", ""); err != nil {
+		t.Fatalf("StoreAccessToken(%q): %v", first, err)
+	}
+	if err := StoreAccessToken(second, "token-2", ""); err != nil {
+		t.Fatalf("StoreAccessToken(%q): %v", second, err)
+	}
+	// second is now the current backend, having been logged into most recently.
+
+	// Deleting the non-current account must not disturb which backend is current.
+	if err := DeleteAccessToken(first); err != nil {
+		t.Fatalf("DeleteAccessToken(%q): %v", first, err)
+	}
+	gotCurrent, err := GetCurrentCloudURL()
+	if err != nil {
+		t.Fatalf("GetCurrentCloudURL: %v", err)
+	}
+	if gotCurrent != second {
+		t.Errorf("GetCurrentCloudURL() = %q; expected %q (unaffected by deleting a non-current account)", gotCurrent, second)
+	}
+
+	// Deleting the current account must clear Current.
+	if err := DeleteAccessToken(second); err != nil {
+		t.Fatalf("DeleteAccessToken(%q): %v", second, err)
+	}
+	gotCurrent, err = GetCurrentCloudURL()
+	if err != nil {
+		t.Fatalf("GetCurrentCloudURL: %v", err)
+	}
+	if gotCurrent != "" {
+		t.Errorf("GetCurrentCloudURL() = %q; expected empty after deleting the current backend", gotCurrent)
+	}
+}
+
+func TestGetAccountForUnknownURLIsZeroValue(t *testing.T) {
+	defer withTempHome(t)()
+
+	account, err := GetAccount("https://never-logged-into.example.com")
+	if err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	if account != (Account{}) {
+		t.Errorf("GetAccount for an unknown URL = %+v; expected the zero value", account)
+	}
+}